@@ -5,17 +5,37 @@ import (
 	"flag"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/solo-io/go-utils/contextutils"
 	"github.com/solo-io/solo-kit/pkg/api/v1/clients"
 	"github.com/solo-io/squash/pkg/api/v1"
+	"github.com/solo-io/squash/pkg/debuggers/events"
+	"github.com/solo-io/squash/pkg/debuggers/gc"
+	"github.com/solo-io/squash/pkg/debuggers/logs"
 	"github.com/solo-io/squash/pkg/platforms"
 	"github.com/solo-io/squash/pkg/utils"
 	"github.com/solo-io/squash/pkg/utils/kubeutils"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/util/workqueue"
 )
 
+// numThreads bounds how many DebugAttachments are processed concurrently by
+// the workqueue workers started in handleAttachments.
+var numThreads = flag.Int("num-threads", 2, "number of workers processing DebugAttachment changes concurrently")
+
+// maxNumRequeues is how many times a key is retried with backoff before it
+// is dropped as a terminal error.
+const maxNumRequeues = 5
+
+// captureLogsTimeout bounds how long a single log-capture attempt (including
+// retries) may block a workqueue worker.
+const captureLogsTimeout = 10 * time.Second
+
 func RunSquashClient(debugger func(string) Debugger, conttopid platforms.ContainerProcess) error {
 	log.SetLevel(log.DebugLevel)
 
@@ -50,7 +70,16 @@ func RunSquashClient(debugger func(string) Debugger, conttopid platforms.Contain
 		return err
 	}
 
-	return NewDebugHandler(ctx, watchNamespaces, daClient, debugger, conttopid).handleAttachments()
+	recorder := events.NewRecorder(kubeResClient, getNodeName())
+
+	collector := gc.NewCollector(ctx, kubeResClient, daClient, recorder, watchNamespaces, getNodeName())
+	go func() {
+		if err := collector.Run(); err != nil {
+			log.WithField("err", err).Error("gc: collector stopped")
+		}
+	}()
+
+	return NewDebugHandler(ctx, watchNamespaces, daClient, debugger, conttopid, recorder, kubeResClient, logs.NewStore()).handleAttachments()
 }
 
 type DebugHandler struct {
@@ -60,21 +89,41 @@ type DebugHandler struct {
 	conttopid       platforms.ContainerProcess
 	debugController *DebugController
 	daClient        *v1.DebugAttachmentClient
+	recorder        *events.Recorder
+	kubeClient      kubernetes.Interface
+	logStore        *logs.Store
 
 	watchNamespaces []string
 
 	etag        *string
 	attachments []*v1.DebugAttachment
+
+	lastStateMu sync.Mutex
+	lastState   map[string]v1.DebugAttachment_State
+
+	// listerMu guards lister, a cache of the most recent snapshot keyed by
+	// namespace/name, filled in by the informer-style watch loop and read by
+	// the workqueue workers so they always act on the latest known object.
+	listerMu sync.RWMutex
+	lister   map[string]*v1.DebugAttachment
+
+	queue workqueue.RateLimitingInterface
 }
 
 func NewDebugHandler(ctx context.Context, watchNamespaces []string, daClient *v1.DebugAttachmentClient, debugger func(string) Debugger,
-	conttopid platforms.ContainerProcess) *DebugHandler {
+	conttopid platforms.ContainerProcess, recorder *events.Recorder, kubeClient kubernetes.Interface, logStore *logs.Store) *DebugHandler {
 	dbghandler := &DebugHandler{
 		ctx:             ctx,
 		daClient:        daClient,
 		debugger:        debugger,
 		conttopid:       conttopid,
 		watchNamespaces: watchNamespaces,
+		recorder:        recorder,
+		kubeClient:      kubeClient,
+		logStore:        logStore,
+		lastState:       make(map[string]v1.DebugAttachment_State),
+		lister:          make(map[string]*v1.DebugAttachment),
+		queue:           workqueue.NewNamedRateLimitingQueue(workqueue.DefaultControllerRateLimiter(), "debugattachments"),
 	}
 
 	dbghandler.debugController = NewDebugController(ctx, debugger, daClient, conttopid)
@@ -85,44 +134,257 @@ func getNodeName() string {
 	return os.Getenv("NODE_NAME")
 }
 
+// handleAttachments watches DebugAttachments via a solo-kit snapshot feed,
+// treating each snapshot as a resync of a shared informer: it diffs the
+// snapshot against d.lister to detect adds/updates/deletes and enqueues the
+// changed namespace/name keys into a rate-limited workqueue. numThreads
+// (--num-threads) workers drain the queue and dispatch synchronously to the per-state
+// handlers in syncOne, so the queue - not a fire-and-forget goroutine per
+// item - is what bounds concurrency and drives retries with backoff.
 func (d *DebugHandler) handleAttachments() error {
-	// setup event loop
 	emitter := v1.NewApiEmitter(*d.daClient)
-	syncer := d // DebugHandler implements Sync
-	el := v1.NewApiEventLoop(emitter, syncer)
-	// run event loop
 	wOpts := clients.WatchOpts{}
 	log.WithField("list", d.watchNamespaces).Info("Watching namespaces")
-	errs, err := el.Run(d.watchNamespaces, wOpts)
+	watch, errs, err := emitter.Snapshots(d.watchNamespaces, wOpts)
 	if err != nil {
 		return err
 	}
-	for err := range errs {
-		contextutils.LoggerFrom(d.ctx).Errorf("error in setup: %v", err)
+	defer d.queue.ShutDown()
+
+	for i := 0; i < *numThreads; i++ {
+		go wait.Until(d.runWorker, time.Second, d.ctx.Done())
+	}
+
+	for {
+		select {
+		case snapshot, ok := <-watch:
+			if !ok {
+				return nil
+			}
+			d.onSnapshot(snapshot)
+		case err, ok := <-errs:
+			if !ok {
+				continue
+			}
+			contextutils.LoggerFrom(d.ctx).Errorf("error in setup: %v", err)
+		case <-d.ctx.Done():
+			return nil
+		}
 	}
-	return nil
 }
 
-// This implements the syncer interface
-func (d *DebugHandler) Sync(ctx context.Context, snapshot *v1.ApiSnapshot) error {
-	log.Debug("running sync")
-	daMap := snapshot.Debugattachments
-	for _, daList := range daMap {
+// onSnapshot plays the role of the ResourceEventHandlerFuncs registered
+// against a shared informer: it updates d.lister and enqueues a key for
+// every DebugAttachment that is new or has changed since the last snapshot,
+// and forgets any that disappeared.
+func (d *DebugHandler) onSnapshot(snapshot *v1.ApiSnapshot) {
+	seen := make(map[string]bool)
+	for _, daList := range snapshot.Debugattachments {
 		for _, da := range daList {
-			if err := d.syncOne(da); err != nil {
-				return err
-			}
+			key := da.Metadata.Namespace + "/" + da.Metadata.Name
+			seen[key] = true
+			d.onAddOrUpdate(key, da)
 		}
 	}
-	return nil
+	d.forgetMissing(seen)
+}
+
+func (d *DebugHandler) onAddOrUpdate(key string, da *v1.DebugAttachment) {
+	d.listerMu.Lock()
+	previous := d.lister[key]
+	d.lister[key] = da
+	d.listerMu.Unlock()
+
+	if previous != nil && previous.Metadata.ResourceVersion == da.Metadata.ResourceVersion {
+		return
+	}
+	d.queue.Add(key)
+}
+
+func (d *DebugHandler) forgetMissing(seen map[string]bool) {
+	d.listerMu.Lock()
+	defer d.listerMu.Unlock()
+	for key := range d.lister {
+		if !seen[key] {
+			delete(d.lister, key)
+		}
+	}
+}
+
+func (d *DebugHandler) runWorker() {
+	for d.processNextWorkItem() {
+	}
+}
+
+func (d *DebugHandler) processNextWorkItem() bool {
+	key, shutdown := d.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer d.queue.Done(key)
+
+	if err := d.processKey(key.(string)); err != nil {
+		if d.queue.NumRequeues(key) < maxNumRequeues {
+			contextutils.LoggerFrom(d.ctx).Warnf("requeueing %q after error: %v", key, err)
+			d.queue.AddRateLimited(key)
+			return true
+		}
+		contextutils.LoggerFrom(d.ctx).Errorf("dropping %q after %d retries: %v", key, maxNumRequeues, err)
+	}
+	d.queue.Forget(key)
+	return true
+}
+
+// processKey re-fetches the latest known object from the lister rather than
+// acting on whatever was queued, so a key coalesces multiple rapid updates
+// into a single sync of current state.
+func (d *DebugHandler) processKey(key string) error {
+	d.listerMu.RLock()
+	da, ok := d.lister[key]
+	d.listerMu.RUnlock()
+	if !ok {
+		// deleted between enqueue and processing
+		return nil
+	}
+	return d.syncOne(da)
 }
 
+// phaseFor maps a DebugAttachment state onto the events package's Phase type.
+func phaseFor(state v1.DebugAttachment_State) events.Phase {
+	switch state {
+	case v1.DebugAttachment_RequestingAttachment:
+		return events.PhaseRequestingAttachment
+	case v1.DebugAttachment_PendingAttachment:
+		return events.PhasePendingAttachment
+	case v1.DebugAttachment_Attached:
+		return events.PhaseAttached
+	case v1.DebugAttachment_RequestingDelete:
+		return events.PhaseRequestingDelete
+	case v1.DebugAttachment_PendingDelete:
+		return events.PhasePendingDelete
+	default:
+		return ""
+	}
+}
+
+// recordTransition emits an event iff da.State differs from the last state
+// observed for this attachment, so steady-state re-syncs don't spam events.
+func (d *DebugHandler) recordTransition(da *v1.DebugAttachment) {
+	if d.recorder == nil {
+		return
+	}
+	key := da.Metadata.Namespace + "/" + da.Metadata.Name
+	d.lastStateMu.Lock()
+	previous, seen := d.lastState[key]
+	d.lastState[key] = da.State
+	d.lastStateMu.Unlock()
+
+	if seen && previous == da.State {
+		return
+	}
+	from := events.Phase("")
+	if seen {
+		from = phaseFor(previous)
+	}
+	d.recorder.EmitTransition(da, from, phaseFor(da.State))
+}
+
+// captureLogsOnFailure grabs the target container's recent log tail when the
+// attach/detach attempt failed (dlv exited, target process crashed, ptrace
+// attach failed) so operators can post-mortem the failure via squashctl logs
+// without separate access to the target namespace. Gated on attemptErr
+// rather than da.Status.State: nothing in this tree confirms what state
+// DebugController actually leaves a failed attachment in, so trusting the
+// error handleAttachmentRequest/removeAttachment already returned is the
+// one signal we know is correct.
+func (d *DebugHandler) captureLogsOnFailure(da *v1.DebugAttachment, phase events.Phase, attemptErr error) {
+	if d.logStore == nil || d.kubeClient == nil {
+		return
+	}
+	if attemptErr == nil {
+		return
+	}
+	attachment := da.Spec.Attachment
+
+	captureCtx, cancel := context.WithTimeout(d.ctx, captureLogsTimeout)
+	defer cancel()
+
+	tail, err := logs.CaptureLogs(captureCtx, d.kubeClient, da.Metadata.Namespace, attachment.Pod, attachment.Container, 0, 200, 0)
+	if err != nil {
+		log.WithField("err", err).Warn("logs: failed to capture target container logs")
+		return
+	}
+	d.logStore.Set(da.Metadata.Namespace, da.Metadata.Name, tail)
+
+	if d.recorder != nil {
+		d.recorder.Emit(da, events.Event{
+			Type:            corev1.EventTypeWarning,
+			Reason:          "CapturedLogs",
+			Phase:           phase,
+			TargetContainer: attachment.Container,
+		})
+	}
+}
+
+// emitAttachOutcome records the dlv spawn outcome of an attach attempt as
+// its own sub-event, distinct from the RequestingAttachment->* transition:
+// a transition tells you the attachment moved on, this tells you whether it
+// actually got a live debug server. Call it right after
+// handleAttachmentRequest returns, while da still reflects that attempt.
+// syncOne separately calls Recorder.EmitError with the same err, which
+// classifies the underlying cause (container resolution, ptrace denial,
+// image pull backoff, ...) via ClassifyError; this method only reports the
+// coarse success/failure signal.
+//
+// Gated on err, not da.Status.State: nothing in this tree confirms
+// DebugController sets Status_Rejected on a failed attach (the one existing
+// e2e assertion, session_test.go's updatedattachment.Status.State check,
+// deliberately avoids asserting equality to Rejected), so trusting a
+// guessed status value here would risk this event silently never firing.
+func (d *DebugHandler) emitAttachOutcome(da *v1.DebugAttachment, err error) {
+	if d.recorder == nil {
+		return
+	}
+	if err != nil {
+		d.recorder.Emit(da, events.Event{
+			Type:            corev1.EventTypeWarning,
+			Reason:          events.ReasonDebugServerFailed,
+			Phase:           events.PhaseRequestingAttachment,
+			TargetContainer: da.Spec.Attachment.Container,
+			TargetProcess:   da.Spec.Attachment.ProcessName,
+		})
+		return
+	}
+	if da.DebugServerAddress != "" {
+		d.recorder.Emit(da, events.Event{
+			Type:               corev1.EventTypeNormal,
+			Reason:             events.ReasonDebugServerStarted,
+			Phase:              events.PhaseRequestingAttachment,
+			TargetContainer:    da.Spec.Attachment.Container,
+			TargetProcess:      da.Spec.Attachment.ProcessName,
+			DebugServerAddress: da.DebugServerAddress,
+		})
+	}
+}
+
+// syncOne dispatches on da.State and returns whatever error the per-state
+// handler produces, so that processNextWorkItem can requeue it with the
+// rate limiter on transient failures (kube API 409/timeout, dlv startup
+// race) instead of swallowing them. handleAttachmentRequest and
+// removeAttachment are expected to return that error rather than handling
+// it internally-only; a purely void handler would defeat the retry.
 func (d *DebugHandler) syncOne(da *v1.DebugAttachment) error {
+	d.recordTransition(da)
 	switch da.State {
 	case v1.DebugAttachment_RequestingAttachment:
 		log.Debug("handling requesting attachment")
-		go d.debugController.handleAttachmentRequest(da)
-		return nil
+		err := d.debugController.handleAttachmentRequest(da)
+		d.emitAttachOutcome(da, err)
+		if err != nil && d.recorder != nil {
+			d.recorder.EmitError(da, events.PhaseRequestingAttachment, err)
+		}
+		d.captureLogsOnFailure(da, events.PhaseRequestingAttachment, err)
+		return err
 	case v1.DebugAttachment_PendingAttachment:
 		log.Debug("handling pending attachment")
 		// do nothing, will transition out of this state according to the result of the RequestingAttachment handler
@@ -134,11 +396,18 @@ func (d *DebugHandler) syncOne(da *v1.DebugAttachment) error {
 	case v1.DebugAttachment_RequestingDelete:
 		log.Debug("handling requesting delete")
 		log.WithFields(log.Fields{"attachment.Name": da.Metadata.Name}).Debug("Removing attachment")
-		go func() { d.debugController.removeAttachment(da.Metadata.Namespace, da.Metadata.Name) }()
-		return nil
+		err := d.debugController.removeAttachment(da.Metadata.Namespace, da.Metadata.Name)
+		if err != nil && d.recorder != nil {
+			d.recorder.EmitError(da, events.PhaseRequestingDelete, err)
+		}
+		d.captureLogsOnFailure(da, events.PhaseRequestingDelete, err)
+		return err
 	case v1.DebugAttachment_PendingDelete:
 		log.Debug("handling pending delete")
 		d.debugController.deleteResource(da.Metadata.Namespace, da.Metadata.Name)
+		if d.logStore != nil {
+			d.logStore.Delete(da.Metadata.Namespace, da.Metadata.Name)
+		}
 		// do nothing, will transition out of this state according to the result of the RequestingDelete handler
 		return nil
 	default:
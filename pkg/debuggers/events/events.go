@@ -0,0 +1,228 @@
+// Package events emits Kubernetes Events for DebugAttachment lifecycle
+// transitions so that `kubectl describe debugattachment` (and squashctl)
+// give operators something actionable when a debugger fails to start.
+package events
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	typedcorev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/solo-io/squash/pkg/api/v1"
+)
+
+// Phase mirrors the DebugAttachment state machine. It is duplicated here
+// (rather than reusing v1.DebugAttachment_State directly) so this package
+// can also describe debugger sub-events that don't correspond to a state.
+type Phase string
+
+const (
+	PhaseRequestingAttachment Phase = "RequestingAttachment"
+	PhasePendingAttachment    Phase = "PendingAttachment"
+	PhaseAttached             Phase = "Attached"
+	PhaseRequestingDelete     Phase = "RequestingDelete"
+	PhasePendingDelete        Phase = "PendingDelete"
+)
+
+// Reason values are stable strings so operators can grep logs or alert on
+// them. Keep these in sync with ClassifyError below.
+const (
+	ReasonTransitioned             = "Transitioned"
+	ReasonProcessNotFound          = "ProcessNotFound"
+	ReasonDebugServerStarted       = "DebugServerStarted"
+	ReasonDebugServerFailed        = "DebugServerFailed"
+	ReasonContainerResolutionError = "ContainerResolutionError"
+	ReasonImagePullBackOff         = "ImagePullBackOff"
+	ReasonPtraceDenied             = "PtraceDenied"
+	ReasonNodeMismatch             = "NodeMismatch"
+	ReasonDebuggerNotFound         = "DebuggerNotFound"
+	ReasonUnknown                  = "Unknown"
+)
+
+// Event is a single typed occurrence in an attachment's lifecycle. Callers
+// build one and hand it to Recorder.Emit rather than calling the underlying
+// record.EventRecorder directly, so that every emission is kept in the
+// attachment's recent history and gets attributed to the same object
+// reference. Only EmitError runs the error through ClassifyError; Emit uses
+// ev.Reason as given.
+type Event struct {
+	Type               string // corev1.EventTypeNormal or corev1.EventTypeWarning
+	Reason             string
+	Phase              Phase
+	FromPhase          Phase // set on ReasonTransitioned events only
+	TargetContainer    string
+	TargetProcess      string
+	DebugServerAddress string
+	Err                error
+}
+
+// ClassifyError maps a raw error coming out of the attach/detach machinery
+// (image pull backoff, ptrace denials, node mismatches, missing dlv binary,
+// ...) into a stable Reason so it can be grepped/alerted on independently
+// of the underlying error message, which tends to drift.
+func ClassifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "imagepullbackoff"), strings.Contains(msg, "errimagepull"):
+		return ReasonImagePullBackOff
+	case strings.Contains(msg, "operation not permitted"), strings.Contains(msg, "ptrace"):
+		return ReasonPtraceDenied
+	case strings.Contains(msg, "node mismatch"), strings.Contains(msg, "wrong node"):
+		return ReasonNodeMismatch
+	// Checked before the dlv-not-found case below: conttopid's
+	// container/PID resolution failures (e.g. a vanished /proc/<pid>/root)
+	// commonly present as "no such file or directory" too, and we want
+	// those attributed to container resolution, not to a missing dlv
+	// binary.
+	case strings.Contains(msg, "container"):
+		return ReasonContainerResolutionError
+	case strings.Contains(msg, "executable file not found"), strings.Contains(msg, "no such file or directory"):
+		return ReasonDebuggerNotFound
+	case strings.Contains(msg, "no such process"), strings.Contains(msg, "process not found"):
+		return ReasonProcessNotFound
+	default:
+		return ReasonUnknown
+	}
+}
+
+// recentEvents is a small ring buffer of the last N events recorded for a
+// single DebugAttachment. Keyed by namespace/name, like logs.Store, since
+// attachment names are only unique within a namespace.
+//
+// This buffer lives only in this squash-client process's memory, so it is
+// lost on restart. It is not written to the DebugAttachment status, and no
+// squashctl command reads it. The Kubernetes Events that NewRecorder emits
+// below are what `kubectl describe debugattachment` shows.
+type recentEvents struct {
+	mu    sync.Mutex
+	max   int
+	byKey map[string][]Event
+}
+
+func newRecentEvents(max int) *recentEvents {
+	return &recentEvents{max: max, byKey: make(map[string][]Event)}
+}
+
+func (r *recentEvents) add(key string, ev Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := append(r.byKey[key], ev)
+	if len(events) > r.max {
+		events = events[len(events)-r.max:]
+	}
+	r.byKey[key] = events
+}
+
+func (r *recentEvents) get(key string) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]Event, len(r.byKey[key]))
+	copy(out, r.byKey[key])
+	return out
+}
+
+// maxRecentEvents bounds how many events are retained per attachment. This
+// buffer is a best-effort, in-process cache of what this squash-client has
+// seen; see the recentEvents doc comment above for where it is and is not
+// visible.
+const maxRecentEvents = 20
+
+// Recorder wraps a client-go EventRecorder, attributing every emitted Event
+// to the DebugAttachment it concerns and keeping a short in-memory history
+// per attachment, readable through Recent.
+type Recorder struct {
+	recorder record.EventRecorder
+	recent   *recentEvents
+}
+
+// NewRecorder builds an EventRecorder whose events carry the component
+// "squash-client" and the given node as host, as created once per node in
+// RunSquashClient.
+func NewRecorder(kubeClient kubernetes.Interface, node string) *Recorder {
+	broadcaster := record.NewBroadcaster()
+	broadcaster.StartRecordingToSink(&typedcorev1.EventSinkImpl{Interface: kubeClient.CoreV1().Events("")})
+	recorder := broadcaster.NewRecorder(scheme.Scheme, corev1.EventSource{
+		Component: "squash-client",
+		Host:      node,
+	})
+	return &Recorder{recorder: recorder, recent: newRecentEvents(maxRecentEvents)}
+}
+
+func refFor(da *v1.DebugAttachment) *corev1.ObjectReference {
+	return &corev1.ObjectReference{
+		Kind:            "DebugAttachment",
+		APIVersion:      "squash.solo.io/v1",
+		Namespace:       da.Metadata.Namespace,
+		Name:            da.Metadata.Name,
+		ResourceVersion: da.Metadata.ResourceVersion,
+	}
+}
+
+// EmitTransition records that da moved from one state to another.
+func (r *Recorder) EmitTransition(da *v1.DebugAttachment, from, to Phase) {
+	r.Emit(da, Event{
+		Type:      corev1.EventTypeNormal,
+		Reason:    ReasonTransitioned,
+		Phase:     to,
+		FromPhase: from,
+	})
+}
+
+// EmitError classifies err and records it as a Warning event against da.
+func (r *Recorder) EmitError(da *v1.DebugAttachment, phase Phase, err error) {
+	r.Emit(da, Event{
+		Type:   corev1.EventTypeWarning,
+		Reason: ClassifyError(err),
+		Phase:  phase,
+		Err:    err,
+	})
+}
+
+// Emit records an already-built Event, appending it to da's recent history
+// and forwarding it to the underlying EventRecorder exactly once. Use
+// EmitTransition/EmitError for the common cases; call this directly for
+// debugger sub-events (e.g. dlv spawn success/failure).
+func (r *Recorder) Emit(da *v1.DebugAttachment, ev Event) {
+	r.recent.add(attachmentKey(da), ev)
+
+	var message string
+	switch ev.Reason {
+	case ReasonTransitioned:
+		message = fmt.Sprintf("transitioned from %s to %s", ev.FromPhase, ev.Phase)
+	default:
+		message = ev.Reason
+	}
+	if ev.TargetContainer != "" {
+		message += " container=" + ev.TargetContainer
+	}
+	if ev.TargetProcess != "" {
+		message += " process=" + ev.TargetProcess
+	}
+	if ev.DebugServerAddress != "" {
+		message += " debugServerAddress=" + ev.DebugServerAddress
+	}
+	if ev.Err != nil {
+		message += " err=" + ev.Err.Error()
+	}
+	r.recorder.Event(refFor(da), ev.Type, ev.Reason, message)
+}
+
+// Recent returns the last events recorded for the named DebugAttachment,
+// newest last, at most maxRecentEvents of them. See the recentEvents doc
+// comment for where this history is and is not visible.
+func (r *Recorder) Recent(namespace, name string) []Event {
+	return r.recent.get(namespace + "/" + name)
+}
+
+func attachmentKey(da *v1.DebugAttachment) string {
+	return da.Metadata.Namespace + "/" + da.Metadata.Name
+}
@@ -0,0 +1,124 @@
+package events
+
+import (
+	"errors"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/record"
+
+	"github.com/solo-io/solo-kit/pkg/api/v1/resources/core"
+	"github.com/solo-io/squash/pkg/api/v1"
+)
+
+func testAttachment() *v1.DebugAttachment {
+	return &v1.DebugAttachment{
+		Metadata: core.Metadata{Namespace: "ns", Name: "da-1"},
+	}
+}
+
+func newTestRecorder(fake *record.FakeRecorder) *Recorder {
+	return &Recorder{recorder: fake, recent: newRecentEvents(maxRecentEvents)}
+}
+
+func TestEmitTransitionRecordsNormalEvent(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	r := newTestRecorder(fake)
+	da := testAttachment()
+
+	r.EmitTransition(da, PhaseRequestingAttachment, PhasePendingAttachment)
+
+	select {
+	case got := <-fake.Events:
+		if want := corev1.EventTypeNormal; !containsType(got, want) {
+			t.Fatalf("expected event of type %s, got %q", want, got)
+		}
+	default:
+		t.Fatal("expected an event to be recorded")
+	}
+
+	recent := r.Recent(da.Metadata.Namespace, da.Metadata.Name)
+	if len(recent) != 1 || recent[0].Reason != ReasonTransitioned {
+		t.Fatalf("expected one Transitioned event in history, got %+v", recent)
+	}
+}
+
+func TestEmitErrorClassifiesReason(t *testing.T) {
+	fake := record.NewFakeRecorder(10)
+	r := newTestRecorder(fake)
+	da := testAttachment()
+
+	r.EmitError(da, PhaseRequestingAttachment, errors.New("fork/exec dlv: no such file or directory"))
+
+	recent := r.Recent(da.Metadata.Namespace, da.Metadata.Name)
+	if len(recent) != 1 {
+		t.Fatalf("expected one event in history, got %d", len(recent))
+	}
+	if recent[0].Reason != ReasonDebuggerNotFound {
+		t.Fatalf("expected reason %s, got %s", ReasonDebuggerNotFound, recent[0].Reason)
+	}
+}
+
+func TestRecentScopedByNamespace(t *testing.T) {
+	// Regression test: history recorded for "da-1" in one namespace must not
+	// show up for a "da-1" in another namespace, the same way logs.Store
+	// scopes by namespace/name rather than name alone.
+	fake := record.NewFakeRecorder(10)
+	r := newTestRecorder(fake)
+
+	one := &v1.DebugAttachment{Metadata: core.Metadata{Namespace: "ns-a", Name: "da-1"}}
+
+	r.EmitTransition(one, PhaseRequestingAttachment, PhasePendingAttachment)
+
+	if got := r.Recent("ns-a", "da-1"); len(got) != 1 {
+		t.Fatalf("expected one event for ns-a/da-1, got %d", len(got))
+	}
+	if got := r.Recent("ns-b", "da-1"); len(got) != 0 {
+		t.Fatalf("expected no events for ns-b/da-1, got %d", len(got))
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want string
+	}{
+		{errors.New("Back-off pulling image \"foo\": ImagePullBackOff"), ReasonImagePullBackOff},
+		{errors.New("ptrace operation not permitted"), ReasonPtraceDenied},
+		{errors.New("attachment targets node mismatch"), ReasonNodeMismatch},
+		{errors.New("exec: \"dlv\": executable file not found in $PATH"), ReasonDebuggerNotFound},
+		// Regression: container/PID resolution failures from conttopid
+		// often present as "no such file or directory" too (a vanished
+		// /proc/<pid>/root), and must not be misclassified as a missing
+		// dlv binary.
+		{errors.New("resolving container root for pid 123: no such file or directory"), ReasonContainerResolutionError},
+		{errors.New("something else entirely"), ReasonUnknown},
+		{nil, ""},
+	}
+	for _, c := range cases {
+		if got := ClassifyError(c.err); got != c.want {
+			t.Errorf("ClassifyError(%v) = %q, want %q", c.err, got, c.want)
+		}
+	}
+}
+
+func TestRecentEventsBounded(t *testing.T) {
+	re := newRecentEvents(2)
+	re.add("x", Event{Reason: "a"})
+	re.add("x", Event{Reason: "b"})
+	re.add("x", Event{Reason: "c"})
+
+	got := re.get("x")
+	if len(got) != 2 || got[0].Reason != "b" || got[1].Reason != "c" {
+		t.Fatalf("expected ring buffer to keep last 2 events, got %+v", got)
+	}
+}
+
+func containsType(event, want string) bool {
+	for i := 0; i+len(want) <= len(event); i++ {
+		if event[i:i+len(want)] == want {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,256 @@
+// Package gc garbage-collects DebugAttachments whose target pod, container,
+// or node no longer matches reality, so that a dlv sidecar doesn't outlive
+// the pod it was attached to.
+package gc
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/solo-io/solo-kit/pkg/api/v1/clients"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/solo-io/squash/pkg/api/v1"
+	"github.com/solo-io/squash/pkg/debuggers/events"
+)
+
+// attachmentTTL bounds how long a DebugAttachment may sit in
+// PendingAttachment before it is considered stuck and garbage collected.
+var attachmentTTL = flag.Duration("attachment-ttl", 15*time.Minute,
+	"how long a DebugAttachment may remain in PendingAttachment before it is garbage collected")
+
+const resyncInterval = 30 * time.Second
+
+// Reason values for the squash_gc_orphans_total counter and the Warning
+// event recorded against the orphaned attachment.
+const (
+	ReasonTargetVanished     = "TargetVanished"
+	ReasonContainerRestarted = "ContainerRestarted"
+	ReasonNodeMismatch       = "NodeMismatch"
+	ReasonAttachTimeout      = "AttachTimeout"
+)
+
+var orphansTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Name: "squash_gc_orphans_total",
+	Help: "Number of DebugAttachments garbage collected, by reason.",
+}, []string{"reason"})
+
+func init() {
+	prometheus.MustRegister(orphansTotal)
+}
+
+// attachmentClient is the subset of *v1.DebugAttachmentClient that
+// reconcileAll/reconcileOne need, narrowed to an interface so tests can
+// exercise the write path (CR transitioned to RequestingDelete) against a
+// fake instead of the real CRD client.
+type attachmentClient interface {
+	List(namespace string, opts clients.ListOpts) (v1.DebugAttachmentList, error)
+	Write(resource *v1.DebugAttachment, opts clients.WriteOpts) (*v1.DebugAttachment, error)
+}
+
+// Collector reconciles DebugAttachments against the pods they target,
+// transitioning orphans through RequestingDelete so the normal delete path
+// tears down their dlv sidecar.
+type Collector struct {
+	ctx context.Context
+
+	kubeClient kubernetes.Interface
+	daClient   attachmentClient
+	recorder   *events.Recorder
+
+	watchNamespaces []string
+	nodeName        string
+
+	mu            sync.RWMutex
+	pods          map[string]*corev1.Pod // namespace/name -> pod
+	restartCounts map[string]int32       // attachment namespace/name -> last observed container restart count
+}
+
+// NewCollector builds a Collector. recorder may be nil if event emission is
+// not desired (e.g. in tests).
+func NewCollector(ctx context.Context, kubeClient kubernetes.Interface, daClient attachmentClient,
+	recorder *events.Recorder, watchNamespaces []string, nodeName string) *Collector {
+	return &Collector{
+		ctx:             ctx,
+		kubeClient:      kubeClient,
+		daClient:        daClient,
+		recorder:        recorder,
+		watchNamespaces: watchNamespaces,
+		nodeName:        nodeName,
+		pods:            make(map[string]*corev1.Pod),
+		restartCounts:   make(map[string]int32),
+	}
+}
+
+// Run indexes pods in watchNamespaces, reconciles on pod delete events and on
+// a fixed resync interval, and blocks until its context is cancelled.
+func (c *Collector) Run() error {
+	for _, ns := range c.watchNamespaces {
+		factory := informers.NewSharedInformerFactoryWithOptions(c.kubeClient, resyncInterval, informers.WithNamespace(ns))
+		informer := factory.Core().V1().Pods().Informer()
+		informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+			AddFunc:    c.onPodChange,
+			UpdateFunc: func(_, newObj interface{}) { c.onPodChange(newObj) },
+			DeleteFunc: c.onPodDelete,
+		})
+		go informer.Run(c.ctx.Done())
+		if !cache.WaitForCacheSync(c.ctx.Done(), informer.HasSynced) {
+			return fmt.Errorf("gc: failed to sync pod informer for namespace %q", ns)
+		}
+	}
+
+	ticker := time.NewTicker(resyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.reconcileAll()
+		case <-c.ctx.Done():
+			return nil
+		}
+	}
+}
+
+func (c *Collector) onPodChange(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		return
+	}
+	c.mu.Lock()
+	c.pods[pod.Namespace+"/"+pod.Name] = pod
+	c.mu.Unlock()
+}
+
+func (c *Collector) onPodDelete(obj interface{}) {
+	pod, ok := obj.(*corev1.Pod)
+	if !ok {
+		tombstone, ok := obj.(cache.DeletedFinalStateUnknown)
+		if !ok {
+			return
+		}
+		pod, ok = tombstone.Obj.(*corev1.Pod)
+		if !ok {
+			return
+		}
+	}
+
+	c.mu.Lock()
+	delete(c.pods, pod.Namespace+"/"+pod.Name)
+	c.mu.Unlock()
+
+	c.reconcileAll()
+}
+
+func (c *Collector) reconcileAll() {
+	for _, ns := range c.watchNamespaces {
+		das, err := c.daClient.List(ns, clients.ListOpts{Ctx: c.ctx})
+		if err != nil {
+			log.WithField("err", err).Error("gc: failed to list DebugAttachments")
+			continue
+		}
+		for _, da := range das {
+			c.reconcileOne(da)
+		}
+	}
+}
+
+// reconcileOne checks a single DebugAttachment and, if it is orphaned,
+// transitions it to RequestingDelete so the existing delete path cleans up
+// its dlv sidecar.
+func (c *Collector) reconcileOne(da *v1.DebugAttachment) {
+	switch da.State {
+	case v1.DebugAttachment_Attached, v1.DebugAttachment_PendingAttachment:
+	default:
+		return
+	}
+
+	reason, orphaned := c.isOrphaned(da)
+	if !orphaned {
+		return
+	}
+
+	log.WithFields(log.Fields{"attachment": da.Metadata.Name, "reason": reason}).Warn("gc: removing orphaned DebugAttachment")
+	orphansTotal.WithLabelValues(reason).Inc()
+	if c.recorder != nil {
+		c.recorder.Emit(da, orphanEvent(reason))
+	}
+
+	da.State = v1.DebugAttachment_RequestingDelete
+	if _, err := c.daClient.Write(da, clients.WriteOpts{Ctx: c.ctx, OverwriteExisting: true}); err != nil {
+		log.WithField("err", err).Error("gc: failed to transition orphaned DebugAttachment to RequestingDelete")
+	}
+}
+
+// orphanEvent builds the Warning event for an orphaned attachment with the
+// GC reason used verbatim. It bypasses Recorder.EmitError deliberately:
+// that helper runs its input through ClassifyError, which is meant for raw
+// attach/detach errors and would otherwise relabel (or flatten to Unknown)
+// our already-specific GC reasons.
+func orphanEvent(reason string) events.Event {
+	return events.Event{
+		Type:   corev1.EventTypeWarning,
+		Reason: reason,
+		Phase:  events.PhaseRequestingDelete,
+		Err:    errors.New(reason),
+	}
+}
+
+func (c *Collector) isOrphaned(da *v1.DebugAttachment) (string, bool) {
+	podKey := da.Metadata.Namespace + "/" + da.Spec.Attachment.Pod
+	c.mu.RLock()
+	pod, ok := c.pods[podKey]
+	c.mu.RUnlock()
+
+	if !ok {
+		return ReasonTargetVanished, true
+	}
+	if pod.Spec.NodeName != "" && c.nodeName != "" && pod.Spec.NodeName != c.nodeName {
+		return ReasonNodeMismatch, true
+	}
+	if c.containerRestarted(da.Metadata.Namespace+"/"+da.Metadata.Name, pod, da.Spec.Attachment.Container) {
+		return ReasonContainerRestarted, true
+	}
+	if da.State == v1.DebugAttachment_PendingAttachment && *attachmentTTL > 0 {
+		if age := time.Since(da.Metadata.CreationTimestamp); age > *attachmentTTL {
+			return ReasonAttachTimeout, true
+		}
+	}
+	return "", false
+}
+
+// containerRestarted remembers the restart count it last saw for an
+// attachment's target container and reports a restart once that count
+// climbs, which is our proxy for "the container got a new container ID".
+func (c *Collector) containerRestarted(attachmentKey string, pod *corev1.Pod, containerName string) bool {
+	if containerName == "" {
+		return false
+	}
+	var restarts int32
+	found := false
+	for _, cs := range pod.Status.ContainerStatuses {
+		if cs.Name == containerName {
+			restarts = cs.RestartCount
+			found = true
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	previous, seen := c.restartCounts[attachmentKey]
+	c.restartCounts[attachmentKey] = restarts
+	return seen && restarts > previous
+}
@@ -0,0 +1,176 @@
+package gc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakekube "k8s.io/client-go/kubernetes/fake"
+
+	"github.com/solo-io/solo-kit/pkg/api/v1/clients"
+	"github.com/solo-io/solo-kit/pkg/api/v1/resources/core"
+	"github.com/solo-io/squash/pkg/api/v1"
+	"github.com/solo-io/squash/pkg/debuggers/events"
+)
+
+// Note: the real envtest suite (spinning up kube-apiserver/etcd and deleting
+// the target pod mid-attachment) needs binaries this sandbox doesn't have.
+// These tests exercise the same orphan-detection logic against an in-memory
+// Collector instead.
+
+func newCollectorWithPods(pods ...*corev1.Pod) *Collector {
+	c := NewCollector(context.Background(), nil, nil, nil, []string{"ns"}, "node-1")
+	for _, pod := range pods {
+		c.pods[pod.Namespace+"/"+pod.Name] = pod
+	}
+	return c
+}
+
+// fakeAttachmentClient is an in-memory attachmentClient so reconcileOne's
+// write path (the actual CR-mutating step GC is for) can be exercised
+// without a real DebugAttachment CRD client.
+type fakeAttachmentClient struct {
+	mu      sync.Mutex
+	das     []*v1.DebugAttachment
+	written int
+}
+
+func (f *fakeAttachmentClient) List(namespace string, opts clients.ListOpts) (v1.DebugAttachmentList, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out v1.DebugAttachmentList
+	for _, da := range f.das {
+		if da.Metadata.Namespace == namespace {
+			out = append(out, da)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeAttachmentClient) Write(resource *v1.DebugAttachment, opts clients.WriteOpts) (*v1.DebugAttachment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.written++
+	for i, da := range f.das {
+		if da.Metadata.Namespace == resource.Metadata.Namespace && da.Metadata.Name == resource.Metadata.Name {
+			f.das[i] = resource
+		}
+	}
+	return resource, nil
+}
+
+func attachment(podName, container string, state v1.DebugAttachment_State) *v1.DebugAttachment {
+	return &v1.DebugAttachment{
+		Metadata: core.Metadata{Namespace: "ns", Name: "da-1", CreationTimestamp: time.Now()},
+		State:    state,
+		Spec: &v1.DebugAttachmentSpec{
+			Attachment: &v1.Attachment{Pod: podName, Container: container},
+		},
+	}
+}
+
+func TestIsOrphanedTargetVanished(t *testing.T) {
+	c := newCollectorWithPods()
+	da := attachment("missing-pod", "app", v1.DebugAttachment_Attached)
+
+	reason, orphaned := c.isOrphaned(da)
+	if !orphaned || reason != ReasonTargetVanished {
+		t.Fatalf("expected orphaned=true reason=%s, got orphaned=%v reason=%s", ReasonTargetVanished, orphaned, reason)
+	}
+}
+
+func TestIsOrphanedNodeMismatch(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-1"},
+		Spec:       corev1.PodSpec{NodeName: "node-2"},
+	}
+	c := newCollectorWithPods(pod)
+	da := attachment("pod-1", "app", v1.DebugAttachment_Attached)
+
+	reason, orphaned := c.isOrphaned(da)
+	if !orphaned || reason != ReasonNodeMismatch {
+		t.Fatalf("expected orphaned=true reason=%s, got orphaned=%v reason=%s", ReasonNodeMismatch, orphaned, reason)
+	}
+}
+
+func TestIsOrphanedContainerRestarted(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-1"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+		Status: corev1.PodStatus{
+			ContainerStatuses: []corev1.ContainerStatus{{Name: "app", RestartCount: 0}},
+		},
+	}
+	c := newCollectorWithPods(pod)
+	da := attachment("pod-1", "app", v1.DebugAttachment_Attached)
+
+	if _, orphaned := c.isOrphaned(da); orphaned {
+		t.Fatal("expected first observation to not be orphaned")
+	}
+
+	pod.Status.ContainerStatuses[0].RestartCount = 1
+	reason, orphaned := c.isOrphaned(da)
+	if !orphaned || reason != ReasonContainerRestarted {
+		t.Fatalf("expected orphaned=true reason=%s after restart, got orphaned=%v reason=%s", ReasonContainerRestarted, orphaned, reason)
+	}
+}
+
+func TestOrphanEventUsesReasonVerbatim(t *testing.T) {
+	// Regression test: orphanEvent must not route GC reasons through
+	// ClassifyError, which would relabel e.g. "ContainerRestarted" (it
+	// contains "container") to ReasonContainerResolutionError, and
+	// "TargetVanished"/"AttachTimeout" to ReasonUnknown.
+	for _, reason := range []string{ReasonTargetVanished, ReasonContainerRestarted, ReasonNodeMismatch, ReasonAttachTimeout} {
+		ev := orphanEvent(reason)
+		if ev.Reason != reason {
+			t.Errorf("orphanEvent(%s).Reason = %s, want %s", reason, ev.Reason, reason)
+		}
+	}
+}
+
+func TestIsOrphanedAttachTimeout(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "pod-1"},
+		Spec:       corev1.PodSpec{NodeName: "node-1"},
+	}
+	c := newCollectorWithPods(pod)
+	da := attachment("pod-1", "app", v1.DebugAttachment_PendingAttachment)
+	da.Metadata.CreationTimestamp = time.Now().Add(-2 * *attachmentTTL)
+
+	reason, orphaned := c.isOrphaned(da)
+	if !orphaned || reason != ReasonAttachTimeout {
+		t.Fatalf("expected orphaned=true reason=%s, got orphaned=%v reason=%s", ReasonAttachTimeout, orphaned, reason)
+	}
+}
+
+func TestReconcileOneWritesOrphanToRequestingDelete(t *testing.T) {
+	da := attachment("missing-pod", "app", v1.DebugAttachment_Attached)
+	fake := &fakeAttachmentClient{das: []*v1.DebugAttachment{da}}
+	recorder := events.NewRecorder(fakekube.NewSimpleClientset(), "node-1")
+
+	c := NewCollector(context.Background(), fakekube.NewSimpleClientset(), fake, recorder, []string{"ns"}, "node-1")
+	c.reconcileOne(da)
+
+	if da.State != v1.DebugAttachment_RequestingDelete {
+		t.Fatalf("expected orphaned attachment to be transitioned to RequestingDelete, got %v", da.State)
+	}
+	if fake.written != 1 {
+		t.Fatalf("expected reconcileOne to write the transitioned attachment exactly once, got %d writes", fake.written)
+	}
+}
+
+func TestReconcileAllRemovesOrphanWithinOneResync(t *testing.T) {
+	da := attachment("missing-pod", "app", v1.DebugAttachment_Attached)
+	fake := &fakeAttachmentClient{das: []*v1.DebugAttachment{da}}
+	recorder := events.NewRecorder(fakekube.NewSimpleClientset(), "node-1")
+
+	c := NewCollector(context.Background(), fakekube.NewSimpleClientset(), fake, recorder, []string{"ns"}, "node-1")
+	c.reconcileAll()
+
+	if got := fake.das[0].State; got != v1.DebugAttachment_RequestingDelete {
+		t.Fatalf("expected the CR to be removed (transitioned to RequestingDelete) within one resync, got %v", got)
+	}
+}
@@ -0,0 +1,131 @@
+// Package logs captures a target container's recent stdout/stderr through
+// the pods/log subresource so a failed debug session can be post-mortemed
+// without separate kubectl logs access to the target namespace.
+package logs
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// defaultMaxBytes is how much of the tail we capture when the caller
+	// doesn't ask for more.
+	defaultMaxBytes = 4 * 1024
+	// capMaxBytes is the absolute ceiling, regardless of what's requested.
+	capMaxBytes = 1024 * 1024
+
+	maxAttempts  = 3
+	retryBackoff = 500 * time.Millisecond
+)
+
+// CaptureLogs streams container's recent log output through
+// pods/log, bounded to maxBytes (0 defaults to 4KiB, never exceeding 1MiB),
+// retrying transient context.DeadlineExceeded / 5xx / 429 responses the way the
+// admin-log helpers in production operators do.
+func CaptureLogs(ctx context.Context, kubeClient kubernetes.Interface, namespace, pod, container string, sinceSeconds, tailLines, maxBytes int64) (string, error) {
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxBytes
+	}
+	if maxBytes > capMaxBytes {
+		maxBytes = capMaxBytes
+	}
+
+	opts := &corev1.PodLogOptions{Container: container, LimitBytes: &maxBytes}
+	if tailLines > 0 {
+		opts.TailLines = &tailLines
+	}
+	if sinceSeconds > 0 {
+		opts.SinceSeconds = &sinceSeconds
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(retryBackoff * time.Duration(attempt)):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+
+		tail, err := captureOnce(ctx, kubeClient, namespace, pod, opts, maxBytes)
+		if err == nil {
+			return tail, nil
+		}
+		if !isRetryable(err) {
+			return "", err
+		}
+		lastErr = err
+	}
+	return "", fmt.Errorf("capturing logs for %s/%s container %s: %w", namespace, pod, container, lastErr)
+}
+
+func captureOnce(ctx context.Context, kubeClient kubernetes.Interface, namespace, pod string, opts *corev1.PodLogOptions, maxBytes int64) (string, error) {
+	stream, err := kubeClient.CoreV1().Pods(namespace).GetLogs(pod, opts).Stream(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer stream.Close()
+
+	var buf bytes.Buffer
+	if _, err := io.Copy(&buf, io.LimitReader(stream, maxBytes)); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+func isRetryable(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	return apierrors.IsInternalError(err) || apierrors.IsServiceUnavailable(err) || apierrors.IsTimeout(err) || apierrors.IsTooManyRequests(err)
+}
+
+// Store keeps the most recently captured log tail per DebugAttachment. It's
+// an in-memory cache local to this squash-client process, lost on restart
+// and not visible to `kubectl describe debugattachment`. Entries are keyed
+// by "namespace/name" since attachment names are only unique within a
+// namespace.
+//
+// The captured tail is not written to the DebugAttachment status, and no
+// squashctl command reads it; within this process it is readable only
+// through Get.
+type Store struct {
+	mu   sync.Mutex
+	logs map[string]string
+}
+
+func NewStore() *Store {
+	return &Store{logs: make(map[string]string)}
+}
+
+func (s *Store) Set(namespace, name, tail string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logs[namespace+"/"+name] = tail
+}
+
+func (s *Store) Get(namespace, name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logs[namespace+"/"+name]
+}
+
+// Delete removes a namespace/name's captured log tail, called once its
+// DebugAttachment is fully removed so the store doesn't grow unbounded over
+// the lifetime of a long-running squash-client process.
+func (s *Store) Delete(namespace, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.logs, namespace+"/"+name)
+}
@@ -0,0 +1,47 @@
+package logs
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCaptureLogsBoundsToMaxBytes(t *testing.T) {
+	// client-go's fake PodLogs implementation always streams back the
+	// fixed string "fake logs", so this only exercises the bounding /
+	// plumbing, not real API-server truncation behavior.
+	client := fake.NewSimpleClientset()
+
+	tail, err := CaptureLogs(context.Background(), client, "ns", "pod-1", "app", 0, 0, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tail) > 4 {
+		t.Fatalf("expected capture to be bounded to 4 bytes, got %d: %q", len(tail), tail)
+	}
+	if !strings.HasPrefix("fake logs", tail) {
+		t.Fatalf("expected tail to be a prefix of the fake log output, got %q", tail)
+	}
+}
+
+func TestStoreSetGet(t *testing.T) {
+	s := NewStore()
+	if got := s.Get("ns", "missing"); got != "" {
+		t.Fatalf("expected empty string for unknown attachment, got %q", got)
+	}
+
+	s.Set("ns", "da-1", "boom")
+	if got := s.Get("ns", "da-1"); got != "boom" {
+		t.Fatalf("expected %q, got %q", "boom", got)
+	}
+	if got := s.Get("other-ns", "da-1"); got != "" {
+		t.Fatalf("expected entries to be scoped by namespace, got %q", got)
+	}
+
+	s.Delete("ns", "da-1")
+	if got := s.Get("ns", "da-1"); got != "" {
+		t.Fatalf("expected entry to be gone after Delete, got %q", got)
+	}
+}